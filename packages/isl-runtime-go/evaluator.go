@@ -0,0 +1,442 @@
+package islruntime
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/shipgate/isl-runtime-go/parser"
+)
+
+// Scope provides the named bindings an ISL constraint expression can
+// reference: the `input` passed to a behavior, its `result`, the current
+// `state`, and (via `old(...)`) a snapshot of state from before the
+// behavior ran.
+type Scope struct {
+	Input    map[string]interface{}
+	Result   interface{}
+	State    map[string]interface{}
+	OldState map[string]interface{}
+}
+
+// Evaluator evaluates ISL constraint expressions (e.g.
+// `input.email.length > 0`, `result.id != null`,
+// `state.user_count == old(state.user_count) + 1`) against a Scope, so
+// callers get one-line verification instead of hand-evaluating each
+// precondition/postcondition in Go and letting it drift from the spec.
+type Evaluator struct{}
+
+// NewEvaluator creates a new expression evaluator.
+func NewEvaluator() *Evaluator {
+	return &Evaluator{}
+}
+
+// Evaluate parses expr and evaluates it against scope. actual is the
+// expression's value, useful for diagnostics even when it isn't boolean-shaped
+// on its own (e.g. reporting what `input.email` actually was).
+func (e *Evaluator) Evaluate(expr string, scope Scope) (passed bool, actual interface{}, err error) {
+	p := &exprParser{scope: scope}
+	p.lexer = parser.NewLexer(expr)
+	if err := p.advance(); err != nil {
+		return false, nil, err
+	}
+
+	val, err := p.parseOr()
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to evaluate expression %q: %w", expr, err)
+	}
+	if p.tok.Type != parser.TokenEOF {
+		return false, nil, fmt.Errorf("unexpected trailing input in expression %q at %s", expr, p.tok)
+	}
+
+	b, ok := val.(bool)
+	if !ok {
+		return false, val, fmt.Errorf("expression %q did not evaluate to a boolean (got %T)", expr, val)
+	}
+	return b, val, nil
+}
+
+// exprParser is a small precedence-climbing parser/evaluator over the same
+// token stream ISL spec files use (package parser), so `input.email.length`
+// style dotted paths are already single identifier tokens.
+//
+//	expr       := or
+//	or         := and { "||" and }
+//	and        := equality { "&&" equality }
+//	equality   := relational { ("==" | "!=") relational }
+//	relational := additive { (">" | "<" | ">=" | "<=") additive }
+//	additive   := unary { ("+" | "-") unary }
+//	unary      := "!" unary | "-" unary | primary
+//	primary    := NUMBER | STRING | "null" | "old" "(" IDENT ")" | IDENT | "(" expr ")"
+type exprParser struct {
+	lexer *parser.Lexer
+	tok   parser.Token
+	scope Scope
+}
+
+func (p *exprParser) advance() error {
+	tok, err := p.lexer.Next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *exprParser) parseOr() (interface{}, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.Type == parser.TokenOperator && p.tok.Value == "||" {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = asBool(left) || asBool(right)
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (interface{}, error) {
+	left, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.Type == parser.TokenOperator && p.tok.Value == "&&" {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseEquality()
+		if err != nil {
+			return nil, err
+		}
+		left = asBool(left) && asBool(right)
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseEquality() (interface{}, error) {
+	left, err := p.parseRelational()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.Type == parser.TokenOperator && (p.tok.Value == "==" || p.tok.Value == "!=") {
+		op := p.tok.Value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseRelational()
+		if err != nil {
+			return nil, err
+		}
+		eq := valuesEqual(left, right)
+		if op == "==" {
+			left = eq
+		} else {
+			left = !eq
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseRelational() (interface{}, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.Type == parser.TokenOperator && (p.tok.Value == ">" || p.tok.Value == "<" || p.tok.Value == ">=" || p.tok.Value == "<=") {
+		op := p.tok.Value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		lf, lok := toFloat(left)
+		rf, rok := toFloat(right)
+		if !lok || !rok {
+			return nil, fmt.Errorf("operator %q requires numeric operands, got %T and %T", op, left, right)
+		}
+		switch op {
+		case ">":
+			left = lf > rf
+		case "<":
+			left = lf < rf
+		case ">=":
+			left = lf >= rf
+		case "<=":
+			left = lf <= rf
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAdditive() (interface{}, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.Type == parser.TokenOperator && (p.tok.Value == "+" || p.tok.Value == "-") {
+		op := p.tok.Value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		lf, lok := toFloat(left)
+		rf, rok := toFloat(right)
+		if !lok || !rok {
+			return nil, fmt.Errorf("operator %q requires numeric operands, got %T and %T", op, left, right)
+		}
+		if op == "+" {
+			left = lf + rf
+		} else {
+			left = lf - rf
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (interface{}, error) {
+	if p.tok.Type == parser.TokenOperator && p.tok.Value == "!" {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		val, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return !asBool(val), nil
+	}
+	if p.tok.Type == parser.TokenOperator && p.tok.Value == "-" {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		val, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		f, ok := toFloat(val)
+		if !ok {
+			return nil, fmt.Errorf("unary %q requires a numeric operand, got %T", "-", val)
+		}
+		return -f, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (interface{}, error) {
+	switch {
+	case p.tok.Type == parser.TokenNumber:
+		text := p.tok.Value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %w", text, err)
+		}
+		return f, nil
+
+	case p.tok.Type == parser.TokenString:
+		text := p.tok.Value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return text, nil
+
+	case p.tok.Type == parser.TokenIdent && p.tok.Value == "null":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return nil, nil
+
+	case p.tok.Type == parser.TokenIdent && p.tok.Value == "old":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(parser.TokenLParen); err != nil {
+			return nil, err
+		}
+		if p.tok.Type != parser.TokenIdent {
+			return nil, fmt.Errorf("old(...) expects a path, got %s", p.tok)
+		}
+		path := p.tok.Value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(parser.TokenRParen); err != nil {
+			return nil, err
+		}
+		return resolvePath(path, p.scope, true)
+
+	case p.tok.Type == parser.TokenIdent:
+		path := p.tok.Value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return resolvePath(path, p.scope, false)
+
+	case p.tok.Type == parser.TokenLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		val, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(parser.TokenRParen); err != nil {
+			return nil, err
+		}
+		return val, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected token %s", p.tok)
+	}
+}
+
+func (p *exprParser) expect(t parser.TokenType) (parser.Token, error) {
+	if p.tok.Type != t {
+		return parser.Token{}, fmt.Errorf("expected %s, got %s", t, p.tok)
+	}
+	tok := p.tok
+	return tok, p.advance()
+}
+
+// resolvePath resolves a dotted path like "input.email.length" against
+// scope. When useOld is true, a leading "state" segment resolves against
+// scope.OldState (the CaptureInitialState snapshot) instead of scope.State.
+func resolvePath(path string, scope Scope, useOld bool) (interface{}, error) {
+	segments := strings.Split(path, ".")
+	root := segments[0]
+
+	var current interface{}
+	switch root {
+	case "input":
+		current = scope.Input
+	case "result":
+		current = scope.Result
+	case "state":
+		if useOld {
+			current = scope.OldState
+		} else {
+			current = scope.State
+		}
+	default:
+		return nil, fmt.Errorf("unknown root %q (expected input, result, or state)", root)
+	}
+
+	for _, segment := range segments[1:] {
+		if segment == "length" {
+			current = lengthOf(current)
+			continue
+		}
+		var err error
+		current, err = fieldOrKey(current, segment)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %q: %w", path, err)
+		}
+	}
+	return current, nil
+}
+
+// fieldOrKey looks up key on value via reflection: a map key for
+// map[string]interface{}-shaped values, or a struct field (matched
+// case-insensitively, with underscores in key ignored, since Go field names
+// are exported/CamelCase while ISL paths are typically lower_snake).
+func fieldOrKey(value interface{}, key string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		entry := v.MapIndex(reflect.ValueOf(key))
+		if !entry.IsValid() {
+			return nil, nil
+		}
+		return entry.Interface(), nil
+	case reflect.Struct:
+		field := v.FieldByNameFunc(func(name string) bool {
+			return strings.EqualFold(name, key) || strings.EqualFold(name, strings.ReplaceAll(key, "_", ""))
+		})
+		if !field.IsValid() {
+			return nil, nil
+		}
+		return field.Interface(), nil
+	default:
+		return nil, fmt.Errorf("cannot access field %q on %T", key, value)
+	}
+}
+
+// lengthOf implements the `.length` accessor for strings, slices, arrays,
+// and maps.
+func lengthOf(value interface{}) interface{} {
+	if value == nil {
+		return float64(0)
+	}
+	if s, ok := value.(string); ok {
+		return float64(len(s))
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.String:
+		return float64(v.Len())
+	default:
+		return float64(0)
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func asBool(value interface{}) bool {
+	b, _ := value.(bool)
+	return b
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if af, aok := toFloat(a); aok {
+		if bf, bok := toFloat(b); bok {
+			return af == bf
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,116 @@
+package islruntime_test
+
+import (
+	"strings"
+	"testing"
+
+	islruntime "github.com/shipgate/isl-runtime-go"
+	"github.com/shipgate/isl-runtime-go/parser"
+)
+
+func TestEvaluatorEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		expr       string
+		scope      islruntime.Scope
+		wantPassed bool
+		wantActual interface{}
+	}{
+		{
+			name:       "input field comparison",
+			expr:       `input.email.length > 0`,
+			scope:      islruntime.Scope{Input: map[string]interface{}{"email": "a@b.com"}},
+			wantPassed: true,
+			wantActual: true,
+		},
+		{
+			name:       "result equality",
+			expr:       `result.id != null`,
+			scope:      islruntime.Scope{Result: map[string]interface{}{"id": "123"}},
+			wantPassed: true,
+		},
+		{
+			name: "old(state) comparison",
+			expr: `state.user_count == old(state.user_count) + 1`,
+			scope: islruntime.Scope{
+				State:    map[string]interface{}{"user_count": float64(3)},
+				OldState: map[string]interface{}{"user_count": float64(2)},
+			},
+			wantPassed: true,
+		},
+		{
+			name:       "unary minus on a numeric operand",
+			expr:       `-input.balance < 0`,
+			scope:      islruntime.Scope{Input: map[string]interface{}{"balance": float64(5)}},
+			wantPassed: true,
+		},
+		{
+			name:       "unary not",
+			expr:       `!(input.active)`,
+			scope:      islruntime.Scope{Input: map[string]interface{}{"active": false}},
+			wantPassed: true,
+		},
+		{
+			name:       "string literal equality",
+			expr:       `input.status == "ok"`,
+			scope:      islruntime.Scope{Input: map[string]interface{}{"status": "ok"}},
+			wantPassed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := islruntime.NewEvaluator()
+			passed, actual, err := ev.Evaluate(tt.expr, tt.scope)
+			if err != nil {
+				t.Fatalf("Evaluate(%q) returned error: %v", tt.expr, err)
+			}
+			if passed != tt.wantPassed {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.expr, passed, tt.wantPassed)
+			}
+			if tt.wantActual != nil && actual != tt.wantActual {
+				t.Errorf("Evaluate(%q) actual = %v, want %v", tt.expr, actual, tt.wantActual)
+			}
+		})
+	}
+}
+
+func TestEvaluatorEvaluateUnaryMinusRequiresNumeric(t *testing.T) {
+	ev := islruntime.NewEvaluator()
+	_, _, err := ev.Evaluate(`-input.name == 0`, islruntime.Scope{Input: map[string]interface{}{"name": "not a number"}})
+	if err == nil {
+		t.Fatal("expected an error for unary minus on a non-numeric operand, got nil")
+	}
+}
+
+// TestEvaluatorEscapedStringRoundTrip parses a postcondition containing a
+// string literal with a backslash escape through the real parser (rather
+// than handing Evaluate a Go string literal directly), so a regression in
+// how the parser captures a string token's raw span - which previously
+// dropped the closing quote whenever Value (the unescaped contents) was
+// shorter than the source text consumed - would be caught here rather than
+// surfacing downstream as an "unterminated string literal" evaluation error.
+func TestEvaluatorEscapedStringRoundTrip(t *testing.T) {
+	src := `domain D; behavior B { postcondition result.msg == "a\\b"; }`
+	p, err := parser.NewParser(src)
+	if err != nil {
+		t.Fatalf("NewParser: %v", err)
+	}
+	domain, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	expr := domain.Behaviors[0].Postconditions[0].Expr.Text
+	if !strings.HasSuffix(strings.TrimSpace(expr), `"a\\b"`) {
+		t.Fatalf("captured expression text %q lost its closing quote", expr)
+	}
+
+	ev := islruntime.NewEvaluator()
+	passed, _, err := ev.Evaluate(expr, islruntime.Scope{Result: map[string]interface{}{"msg": `a\b`}})
+	if err != nil {
+		t.Fatalf("Evaluate(%q) returned error: %v", expr, err)
+	}
+	if !passed {
+		t.Errorf("Evaluate(%q) = false, want true", expr)
+	}
+}
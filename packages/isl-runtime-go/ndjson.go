@@ -0,0 +1,513 @@
+package islruntime
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ndjsonRecordKind distinguishes the three kinds of lines that make up an
+// NDJSON trace stream.
+type ndjsonRecordKind string
+
+const (
+	ndjsonRecordHeader ndjsonRecordKind = "header"
+	ndjsonRecordEvent  ndjsonRecordKind = "event"
+	ndjsonRecordFooter ndjsonRecordKind = "footer"
+)
+
+// ndjsonRecord is the envelope written for every line of an NDJSON trace.
+// Exactly one of the per-kind field groups is populated, selected by Kind.
+type ndjsonRecord struct {
+	Kind ndjsonRecordKind `json:"kind"`
+
+	// header fields
+	ID           string                 `json:"id,omitempty"`
+	Domain       string                 `json:"domain,omitempty"`
+	StartTime    int64                  `json:"start_time,omitempty"`
+	InitialState map[string]interface{} `json:"initial_state,omitempty"`
+	Sequence     int                    `json:"sequence,omitempty"`
+
+	// event fields
+	Event *TraceEvent `json:"event,omitempty"`
+
+	// footer fields
+	EndTime   int64          `json:"end_time,omitempty"`
+	Metadata  *TraceMetadata `json:"metadata,omitempty"`
+	Continued bool           `json:"continued,omitempty"`
+}
+
+// StreamingEmitter implements Emitter by writing each TraceEvent as a
+// newline-delimited JSON record immediately on emit, rather than buffering
+// every event in memory and serializing one big document the way
+// TraceEmitter.SaveToFile does. This is the emitter to use for services that
+// run for hours or emit millions of events.
+//
+// The first record written is a header carrying id/domain/start_time/
+// initial_state; it is emitted lazily, on the first Emit* call (or Close),
+// so that a CaptureInitialState call made right after construction is still
+// reflected in it. Close writes a footer record carrying end_time/metadata
+// and must always be called, even on failure, so the stream has a
+// well-defined end.
+type StreamingEmitter struct {
+	mu                  sync.Mutex
+	w                   io.Writer
+	traceID             string
+	domain              string
+	behavior            string
+	startTime           int64
+	sequence            int
+	headerWritten       bool
+	pendingInitialState map[string]interface{}
+	eventCounter        atomic.Int64
+	writeErr            error
+}
+
+var _ Emitter = (*StreamingEmitter)(nil)
+
+// NewStreamingEmitter creates a streaming emitter that writes NDJSON records
+// to w as events are emitted.
+func NewStreamingEmitter(w io.Writer, domain, behavior string) *StreamingEmitter {
+	traceID := fmt.Sprintf("trace_%d_%s", time.Now().UnixMilli(), uuid.New().String())
+	return newStreamingEmitter(w, domain, behavior, traceID, 0)
+}
+
+// newStreamingEmitter is the shared constructor behind NewStreamingEmitter
+// and RotatingFileEmitter, which needs to mint a new StreamingEmitter per
+// file while keeping the same trace ID and advancing the sequence number.
+func newStreamingEmitter(w io.Writer, domain, behavior, traceID string, sequence int) *StreamingEmitter {
+	return &StreamingEmitter{
+		w:         w,
+		traceID:   traceID,
+		domain:    domain,
+		behavior:  behavior,
+		startTime: time.Now().UnixMilli(),
+		sequence:  sequence,
+	}
+}
+
+// CaptureInitialState captures the initial state, to be included in the
+// header record once it is written.
+func (se *StreamingEmitter) CaptureInitialState(state map[string]interface{}) {
+	redacted := redactPII(state)
+	se.mu.Lock()
+	se.pendingInitialState = redacted
+	se.mu.Unlock()
+}
+
+// EmitCall emits a function call event.
+func (se *StreamingEmitter) EmitCall(functionName string, args map[string]interface{}) {
+	redactedArgs := redactPII(args)
+	se.emit(TraceEvent{
+		ID:        se.generateEventID(),
+		Type:      TraceEventTypeCall,
+		Timestamp: time.Now().UnixMilli(),
+		Data: map[string]interface{}{
+			"kind":     "call",
+			"function": functionName,
+			"args":     redactedArgs,
+		},
+		Behavior: &se.behavior,
+		Input:    redactedArgs,
+	})
+}
+
+// EmitReturn emits a function return event.
+func (se *StreamingEmitter) EmitReturn(functionName string, result interface{}, durationMs int64) {
+	redactedResult := redactValue(result)
+	se.emit(TraceEvent{
+		ID:        se.generateEventID(),
+		Type:      TraceEventTypeReturn,
+		Timestamp: time.Now().UnixMilli(),
+		Data: map[string]interface{}{
+			"kind":     "return",
+			"function": functionName,
+			"result":   redactedResult,
+			"duration": durationMs,
+		},
+		Behavior: &se.behavior,
+		Output:   redactedResult,
+	})
+}
+
+// EmitStateChange emits a state change event.
+func (se *StreamingEmitter) EmitStateChange(path []string, oldValue, newValue interface{}, source string) {
+	se.emit(TraceEvent{
+		ID:        se.generateEventID(),
+		Type:      TraceEventTypeStateChange,
+		Timestamp: time.Now().UnixMilli(),
+		Data: map[string]interface{}{
+			"kind":     "state_change",
+			"path":     path,
+			"oldValue": redactValue(oldValue),
+			"newValue": redactValue(newValue),
+			"source":   source,
+		},
+		Behavior: &se.behavior,
+	})
+}
+
+// EmitCheck emits a check event (precondition, postcondition, invariant).
+func (se *StreamingEmitter) EmitCheck(expression string, passed bool, category string, expected, actual interface{}, message *string) {
+	data := map[string]interface{}{
+		"kind":       "check",
+		"expression": expression,
+		"passed":     passed,
+		"category":   category,
+	}
+	if expected != nil {
+		data["expected"] = redactValue(expected)
+	}
+	if actual != nil {
+		data["actual"] = redactValue(actual)
+	}
+	if message != nil {
+		data["message"] = *message
+	}
+	se.emit(TraceEvent{
+		ID:        se.generateEventID(),
+		Type:      TraceEventTypeCheck,
+		Timestamp: time.Now().UnixMilli(),
+		Data:      data,
+		Behavior:  &se.behavior,
+	})
+}
+
+// EmitError emits an error event.
+func (se *StreamingEmitter) EmitError(message string, code *string, stack *string) {
+	errorCode := "UNKNOWN"
+	if code != nil {
+		errorCode = *code
+	}
+	se.emit(TraceEvent{
+		ID:        se.generateEventID(),
+		Type:      TraceEventTypeError,
+		Timestamp: time.Now().UnixMilli(),
+		Data: map[string]interface{}{
+			"kind":    "error",
+			"message": message,
+			"code":    errorCode,
+			"stack":   redactPIIValue(stack),
+		},
+		Behavior: &se.behavior,
+		Error: &ErrorInfo{
+			Code:    errorCode,
+			Message: message,
+		},
+	})
+}
+
+// Close writes the footer record for the stream and, if w implements
+// io.Closer, closes it. It must be called exactly once the trace is
+// complete; calling any Emit* method afterward re-opens the stream with a
+// stale footer already written, so callers should treat the emitter as done.
+func (se *StreamingEmitter) Close(passed bool) error {
+	return se.closeInternal(passed, false)
+}
+
+// closeInternal writes the footer, marking it Continued when a
+// RotatingFileEmitter is about to roll over to the next file rather than
+// actually finishing the trace.
+func (se *StreamingEmitter) closeInternal(passed bool, continued bool) error {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.ensureHeaderLocked()
+	endTime := time.Now().UnixMilli()
+	se.writeRecordLocked(ndjsonRecord{
+		Kind:      ndjsonRecordFooter,
+		EndTime:   endTime,
+		Continued: continued,
+		Metadata: &TraceMetadata{
+			TestName:    fmt.Sprintf("%s::%s", se.domain, se.behavior),
+			Scenario:    se.behavior,
+			Version:     "1.0.0",
+			Environment: "runtime",
+			Passed:      passed,
+			Duration:    endTime - se.startTime,
+		},
+	})
+	if c, ok := se.w.(io.Closer); ok {
+		if err := c.Close(); err != nil && se.writeErr == nil {
+			se.writeErr = fmt.Errorf("failed to close trace stream: %w", err)
+		}
+	}
+	return se.writeErr
+}
+
+func (se *StreamingEmitter) emit(event TraceEvent) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.ensureHeaderLocked()
+	se.writeRecordLocked(ndjsonRecord{Kind: ndjsonRecordEvent, Event: &event})
+}
+
+// ensureHeaderLocked writes the header record on the first call; callers
+// must hold se.mu.
+func (se *StreamingEmitter) ensureHeaderLocked() {
+	if se.headerWritten {
+		return
+	}
+	se.writeRecordLocked(ndjsonRecord{
+		Kind:         ndjsonRecordHeader,
+		ID:           se.traceID,
+		Domain:       se.domain,
+		StartTime:    se.startTime,
+		InitialState: se.pendingInitialState,
+		Sequence:     se.sequence,
+	})
+	se.headerWritten = true
+}
+
+// writeRecordLocked marshals rec as a single NDJSON line; callers must hold
+// se.mu. Once a write fails, further writes are skipped so a broken pipe
+// doesn't spend time re-attempting writes that will also fail.
+func (se *StreamingEmitter) writeRecordLocked(rec ndjsonRecord) {
+	if se.writeErr != nil {
+		return
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		se.writeErr = fmt.Errorf("failed to marshal ndjson record: %w", err)
+		return
+	}
+	data = append(data, '\n')
+	if _, err := se.w.Write(data); err != nil {
+		se.writeErr = fmt.Errorf("failed to write ndjson record: %w", err)
+	}
+}
+
+func (se *StreamingEmitter) generateEventID() string {
+	return fmt.Sprintf("evt_%d_%d", se.eventCounter.Add(1), time.Now().UnixMilli())
+}
+
+// NDJSONReader yields the TraceEvents written by a StreamingEmitter (or a
+// single file of a RotatingFileEmitter) one at a time, so `shipgate verify`
+// can consume a trace without loading it fully into memory. Header and
+// footer records are consumed internally; call Header/Footer after Next
+// returns io.EOF to inspect them.
+type NDJSONReader struct {
+	scanner *bufio.Scanner
+	header  *ndjsonRecord
+	footer  *ndjsonRecord
+}
+
+// NewNDJSONReader creates a reader over an NDJSON trace stream produced by
+// StreamingEmitter or RotatingFileEmitter.
+func NewNDJSONReader(r io.Reader) *NDJSONReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &NDJSONReader{scanner: scanner}
+}
+
+// Next returns the next TraceEvent in the stream, or io.EOF once the footer
+// record (or end of input) is reached.
+func (r *NDJSONReader) Next() (*TraceEvent, error) {
+	for r.scanner.Scan() {
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var rec ndjsonRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("failed to parse ndjson record: %w", err)
+		}
+		switch rec.Kind {
+		case ndjsonRecordHeader:
+			header := rec
+			r.header = &header
+		case ndjsonRecordFooter:
+			footer := rec
+			r.footer = &footer
+			return nil, io.EOF
+		case ndjsonRecordEvent:
+			return rec.Event, nil
+		default:
+			return nil, fmt.Errorf("unknown ndjson record kind %q", rec.Kind)
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ndjson stream: %w", err)
+	}
+	return nil, io.EOF
+}
+
+// Header reports the fields carried by the header record, once Next has
+// read past it.
+func (r *NDJSONReader) Header() (id, domain string, startTime int64, initialState map[string]interface{}, ok bool) {
+	if r.header == nil {
+		return "", "", 0, nil, false
+	}
+	return r.header.ID, r.header.Domain, r.header.StartTime, r.header.InitialState, true
+}
+
+// Footer reports the fields carried by the footer record, once Next has
+// returned io.EOF after reading it. continued is true when this file is
+// one segment of a RotatingFileEmitter trace and the next file's header
+// shares the same trace ID.
+func (r *NDJSONReader) Footer() (endTime int64, metadata TraceMetadata, continued bool, ok bool) {
+	if r.footer == nil || r.footer.Metadata == nil {
+		return 0, TraceMetadata{}, false, false
+	}
+	return r.footer.EndTime, *r.footer.Metadata, r.footer.Continued, true
+}
+
+// countingWriter wraps an *os.File so RotatingFileEmitter can track how
+// many bytes have been written to the current file without asking the
+// filesystem.
+type countingWriter struct {
+	f *os.File
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.f.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+func (cw *countingWriter) Close() error {
+	return cw.f.Close()
+}
+
+var _ io.WriteCloser = (*countingWriter)(nil)
+
+// RotatingFileEmitter wraps StreamingEmitter to roll over to a new file
+// once the current one reaches maxBytes, so a single long-running trace
+// doesn't grow one NDJSON file without bound. Every file shares the same
+// trace ID and carries its sequence number in its header, and every file
+// except the last has Continued set on its footer, so a reader can
+// reassemble the full trace by reading basePath, basePath.1, basePath.2,
+// ... in order. Rotation only happens between events, never mid-record, so
+// each file on its own is a complete, parseable NDJSON stream.
+type RotatingFileEmitter struct {
+	mu                  sync.Mutex
+	basePath            string
+	maxBytes            int64
+	domain              string
+	behavior            string
+	traceID             string
+	sequence            int
+	file                *countingWriter
+	stream              *StreamingEmitter
+	pendingInitialState map[string]interface{}
+	firstErr            error
+}
+
+var _ Emitter = (*RotatingFileEmitter)(nil)
+
+// NewRotatingFileEmitter creates a rotating emitter that writes NDJSON
+// trace files at basePath, basePath.1, basePath.2, ..., starting a new
+// file once the current one reaches maxBytes.
+func NewRotatingFileEmitter(basePath string, maxBytes int64, domain, behavior string) (*RotatingFileEmitter, error) {
+	re := &RotatingFileEmitter{
+		basePath: basePath,
+		maxBytes: maxBytes,
+		domain:   domain,
+		behavior: behavior,
+		traceID:  fmt.Sprintf("trace_%d_%s", time.Now().UnixMilli(), uuid.New().String()),
+	}
+	if err := re.openFileLocked(); err != nil {
+		return nil, err
+	}
+	return re, nil
+}
+
+func (re *RotatingFileEmitter) pathForSequence(sequence int) string {
+	if sequence == 0 {
+		return re.basePath
+	}
+	return fmt.Sprintf("%s.%d", re.basePath, sequence)
+}
+
+// openFileLocked creates the file for the current sequence number and
+// starts a new StreamingEmitter over it, replaying the captured initial
+// state so every segment of the trace is independently readable. Callers
+// must hold re.mu.
+func (re *RotatingFileEmitter) openFileLocked() error {
+	path := re.pathForSequence(re.sequence)
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create trace file %s: %w", path, err)
+	}
+	re.file = &countingWriter{f: f}
+	re.stream = newStreamingEmitter(re.file, re.domain, re.behavior, re.traceID, re.sequence)
+	if re.pendingInitialState != nil {
+		re.stream.pendingInitialState = re.pendingInitialState
+	}
+	return nil
+}
+
+// CaptureInitialState captures the initial state, replaying it into every
+// file the trace rolls over to.
+func (re *RotatingFileEmitter) CaptureInitialState(state map[string]interface{}) {
+	redacted := redactPII(state)
+	re.mu.Lock()
+	re.pendingInitialState = redacted
+	re.stream.pendingInitialState = redacted
+	re.mu.Unlock()
+}
+
+func (re *RotatingFileEmitter) EmitCall(functionName string, args map[string]interface{}) {
+	re.withStream(func(s *StreamingEmitter) { s.EmitCall(functionName, args) })
+}
+
+func (re *RotatingFileEmitter) EmitReturn(functionName string, result interface{}, durationMs int64) {
+	re.withStream(func(s *StreamingEmitter) { s.EmitReturn(functionName, result, durationMs) })
+}
+
+func (re *RotatingFileEmitter) EmitStateChange(path []string, oldValue, newValue interface{}, source string) {
+	re.withStream(func(s *StreamingEmitter) { s.EmitStateChange(path, oldValue, newValue, source) })
+}
+
+func (re *RotatingFileEmitter) EmitCheck(expression string, passed bool, category string, expected, actual interface{}, message *string) {
+	re.withStream(func(s *StreamingEmitter) { s.EmitCheck(expression, passed, category, expected, actual, message) })
+}
+
+func (re *RotatingFileEmitter) EmitError(message string, code *string, stack *string) {
+	re.withStream(func(s *StreamingEmitter) { s.EmitError(message, code, stack) })
+}
+
+// withStream runs fn against the current file's StreamingEmitter, then
+// rotates to a new file if the write pushed the current one past maxBytes.
+func (re *RotatingFileEmitter) withStream(fn func(s *StreamingEmitter)) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.firstErr != nil {
+		return
+	}
+	fn(re.stream)
+	if re.stream.writeErr != nil {
+		re.firstErr = re.stream.writeErr
+		return
+	}
+	if re.file.n < re.maxBytes {
+		return
+	}
+	if err := re.stream.closeInternal(false, true); err != nil {
+		re.firstErr = err
+		return
+	}
+	re.sequence++
+	if err := re.openFileLocked(); err != nil {
+		re.firstErr = err
+	}
+}
+
+// Close finalizes the current file's footer (without Continued, since this
+// is the last segment) and returns the first error encountered across the
+// whole trace, if any.
+func (re *RotatingFileEmitter) Close(passed bool) error {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if err := re.stream.closeInternal(passed, false); err != nil && re.firstErr == nil {
+		re.firstErr = err
+	}
+	return re.firstErr
+}
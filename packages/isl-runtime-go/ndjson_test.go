@@ -0,0 +1,129 @@
+package islruntime_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	islruntime "github.com/shipgate/isl-runtime-go"
+)
+
+func TestStreamingEmitterRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	se := islruntime.NewStreamingEmitter(&buf, "Orders", "PlaceOrder")
+	se.CaptureInitialState(map[string]interface{}{"stock": float64(10)})
+	se.EmitCall("PlaceOrder", map[string]interface{}{"sku": "abc"})
+	se.EmitReturn("PlaceOrder", map[string]interface{}{"id": "order-1"}, 5)
+	if err := se.Close(true); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	r := islruntime.NewNDJSONReader(&buf)
+	var events []*islruntime.TraceEvent
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) != 2 {
+		t.Fatalf("len(events) = %d, want 2", len(events))
+	}
+
+	id, domain, _, initialState, ok := r.Header()
+	if !ok {
+		t.Fatal("Header() returned ok=false after reading the full stream")
+	}
+	if id == "" || domain != "Orders" {
+		t.Errorf("Header() = (id=%q, domain=%q), want a non-empty id and domain Orders", id, domain)
+	}
+	if initialState["stock"] != float64(10) {
+		t.Errorf("initialState[\"stock\"] = %v, want 10", initialState["stock"])
+	}
+
+	_, metadata, continued, ok := r.Footer()
+	if !ok {
+		t.Fatal("Footer() returned ok=false after reading the full stream")
+	}
+	if !metadata.Passed {
+		t.Error("Footer metadata.Passed = false, want true")
+	}
+	if continued {
+		t.Error("Footer continued = true, want false for a non-rotating stream")
+	}
+}
+
+func TestRotatingFileEmitterRollsOverAndPreservesTraceID(t *testing.T) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "trace.ndjson")
+
+	re, err := islruntime.NewRotatingFileEmitter(basePath, 1, "Orders", "PlaceOrder")
+	if err != nil {
+		t.Fatalf("NewRotatingFileEmitter: %v", err)
+	}
+	re.CaptureInitialState(map[string]interface{}{"stock": float64(10)})
+	for i := 0; i < 5; i++ {
+		re.EmitCall("PlaceOrder", map[string]interface{}{"n": i})
+		re.EmitReturn("PlaceOrder", map[string]interface{}{"n": i}, 1)
+	}
+	if err := re.Close(true); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if _, err := os.Stat(basePath); err != nil {
+		t.Fatalf("expected %s to exist: %v", basePath, err)
+	}
+	if _, err := os.Stat(basePath + ".1"); err != nil {
+		t.Fatalf("expected rollover to have created %s.1: %v", basePath, err)
+	}
+
+	firstData, err := os.ReadFile(basePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := islruntime.NewNDJSONReader(bytes.NewReader(firstData))
+	for {
+		if _, err := r.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+	}
+	firstID, _, _, _, ok := r.Header()
+	if !ok {
+		t.Fatal("expected a header in the first segment")
+	}
+	_, _, continued, ok := r.Footer()
+	if !ok {
+		t.Fatal("expected a footer in the first segment")
+	}
+	if !continued {
+		t.Error("expected the first segment's footer to have Continued=true")
+	}
+
+	secondData, err := os.ReadFile(basePath + ".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2 := islruntime.NewNDJSONReader(bytes.NewReader(secondData))
+	for {
+		if _, err := r2.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+	}
+	secondID, _, _, _, ok := r2.Header()
+	if !ok {
+		t.Fatal("expected a header in the second segment")
+	}
+	if secondID != firstID {
+		t.Errorf("second segment trace ID = %q, want %q (same trace across rollover)", secondID, firstID)
+	}
+}
@@ -0,0 +1,217 @@
+package islruntime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OTelEmitter exports ISL runtime verification events as OpenTelemetry spans
+// instead of a JSON trace file, so `shipgate verify` traces can be viewed in
+// Jaeger/Tempo and correlated with the rest of a service's telemetry. It
+// implements Emitter, so it is a drop-in replacement for TraceEmitter.
+//
+// EmitCall starts a span, EmitReturn ends it (recording duration_ms),
+// EmitCheck/EmitStateChange add span events, and EmitError records the error
+// and marks the span status as codes.Error. The PII redaction pipeline is
+// applied before any value reaches the exporter.
+//
+// Concurrent or recursive calls to the same function are tracked as a
+// per-functionName stack rather than a single map entry, so two in-flight
+// calls to "f" don't clobber each other's span before EmitReturn runs.
+type OTelEmitter struct {
+	mu                  sync.Mutex
+	tracer              trace.Tracer
+	domain              string
+	behavior            string
+	active              map[string][]activeSpan
+	seqCounter          atomic.Int64
+	pendingInitialState map[string]interface{}
+}
+
+type activeSpan struct {
+	ctx  context.Context
+	span trace.Span
+	seq  int64
+}
+
+// NewOTelEmitter creates an emitter that records spans via tracer. domain and
+// behavior are attached to every span as attributes, mirroring the fields
+// TraceEmitter stamps onto every TraceEvent.
+func NewOTelEmitter(tracer trace.Tracer, domain, behavior string) *OTelEmitter {
+	return &OTelEmitter{
+		tracer:   tracer,
+		domain:   domain,
+		behavior: behavior,
+		active:   make(map[string][]activeSpan),
+	}
+}
+
+var _ Emitter = (*OTelEmitter)(nil)
+
+// CaptureInitialState has no direct OTel equivalent; the redacted state is
+// stashed and attached as attributes on the span started by the next
+// EmitCall.
+func (oe *OTelEmitter) CaptureInitialState(state map[string]interface{}) {
+	redacted := redactPII(state)
+	oe.mu.Lock()
+	oe.pendingInitialState = redacted
+	oe.mu.Unlock()
+}
+
+// EmitCall starts a span named functionName as a root span and attaches
+// domain/behavior and redacted input attributes, mirroring
+// TraceEmitter.EmitCall. It satisfies the Emitter interface, which has no
+// room for a caller-supplied context; use EmitCallContext directly to link
+// the span into an existing trace.
+func (oe *OTelEmitter) EmitCall(functionName string, args map[string]interface{}) {
+	oe.EmitCallContext(context.Background(), functionName, args)
+}
+
+// EmitCallContext starts a span named functionName as a child of ctx, so
+// ISL verification spans show up nested under the application's own
+// request span instead of as disconnected traces, and returns a context
+// carrying the new span for any further EmitCallContext calls made while
+// it's active.
+func (oe *OTelEmitter) EmitCallContext(ctx context.Context, functionName string, args map[string]interface{}) context.Context {
+	redactedArgs := redactPII(args)
+
+	spanCtx, span := oe.tracer.Start(ctx, functionName)
+	span.SetAttributes(
+		attribute.String("isl.domain", oe.domain),
+		attribute.String("isl.behavior", oe.behavior),
+	)
+	for k, v := range redactedArgs {
+		span.SetAttributes(attribute.String("isl.input."+k, fmt.Sprintf("%v", v)))
+	}
+
+	oe.mu.Lock()
+	if oe.pendingInitialState != nil {
+		for k, v := range oe.pendingInitialState {
+			span.SetAttributes(attribute.String("isl.initial_state."+k, fmt.Sprintf("%v", v)))
+		}
+		oe.pendingInitialState = nil
+	}
+	oe.active[functionName] = append(oe.active[functionName], activeSpan{
+		ctx:  spanCtx,
+		span: span,
+		seq:  oe.seqCounter.Add(1),
+	})
+	oe.mu.Unlock()
+
+	return spanCtx
+}
+
+// EmitReturn ends the span started by the matching EmitCall, recording
+// duration_ms and the redacted result.
+func (oe *OTelEmitter) EmitReturn(functionName string, result interface{}, durationMs int64) {
+	span := oe.takeSpan(functionName)
+	if span == nil {
+		return
+	}
+	redactedResult := redactValue(result)
+	span.SetAttributes(
+		attribute.Int64("duration_ms", durationMs),
+		attribute.String("isl.output", fmt.Sprintf("%v", redactedResult)),
+	)
+	span.End()
+}
+
+// EmitStateChange adds a span event carrying the redacted before/after
+// values to the most recently started active span. If no span is active,
+// nothing is recorded.
+func (oe *OTelEmitter) EmitStateChange(path []string, oldValue, newValue interface{}, source string) {
+	oe.withActiveSpan(func(span trace.Span) {
+		span.AddEvent("isl.state_change", trace.WithAttributes(
+			attribute.StringSlice("isl.state_change.path", path),
+			attribute.String("isl.state_change.old", fmt.Sprintf("%v", redactValue(oldValue))),
+			attribute.String("isl.state_change.new", fmt.Sprintf("%v", redactValue(newValue))),
+			attribute.String("isl.state_change.source", source),
+		))
+	})
+}
+
+// EmitCheck adds a span event for a precondition/postcondition/invariant
+// check, carrying the expression, outcome, and category as attributes.
+func (oe *OTelEmitter) EmitCheck(expression string, passed bool, category string, expected, actual interface{}, message *string) {
+	attrs := []attribute.KeyValue{
+		attribute.String("isl.check.expression", expression),
+		attribute.Bool("isl.check.passed", passed),
+		attribute.String("isl.check.category", category),
+	}
+	if expected != nil {
+		attrs = append(attrs, attribute.String("isl.check.expected", fmt.Sprintf("%v", redactValue(expected))))
+	}
+	if actual != nil {
+		attrs = append(attrs, attribute.String("isl.check.actual", fmt.Sprintf("%v", redactValue(actual))))
+	}
+	if message != nil {
+		attrs = append(attrs, attribute.String("isl.check.message", *message))
+	}
+	oe.withActiveSpan(func(span trace.Span) {
+		span.AddEvent("isl.check", trace.WithAttributes(attrs...))
+	})
+}
+
+// EmitError records the error on the active span and marks its status
+// codes.Error, mirroring TraceEmitter.EmitError.
+func (oe *OTelEmitter) EmitError(message string, code *string, stack *string) {
+	errorCode := "UNKNOWN"
+	if code != nil {
+		errorCode = *code
+	}
+	oe.withActiveSpan(func(span trace.Span) {
+		span.SetAttributes(attribute.String("isl.error.code", errorCode))
+		if redactedStack := redactPIIValue(stack); redactedStack != nil {
+			span.SetAttributes(attribute.String("isl.error.stack", *redactedStack))
+		}
+		span.RecordError(fmt.Errorf("%s", message))
+		span.SetStatus(codes.Error, message)
+	})
+}
+
+// takeSpan removes and returns the most recently started, not-yet-ended
+// span for functionName, if any. Using the top of a per-functionName stack
+// (rather than a single map entry) means two in-flight calls to the same
+// function each get their own span instead of the second EmitCall
+// overwriting the first's entry.
+func (oe *OTelEmitter) takeSpan(functionName string) trace.Span {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	stack := oe.active[functionName]
+	if len(stack) == 0 {
+		return nil
+	}
+	top := stack[len(stack)-1]
+	if len(stack) == 1 {
+		delete(oe.active, functionName)
+	} else {
+		oe.active[functionName] = stack[:len(stack)-1]
+	}
+	return top.span
+}
+
+// withActiveSpan runs fn against the most recently started, not-yet-ended
+// span across every functionName, if one exists.
+func (oe *OTelEmitter) withActiveSpan(fn func(span trace.Span)) {
+	oe.mu.Lock()
+	var latest *activeSpan
+	for _, stack := range oe.active {
+		if len(stack) == 0 {
+			continue
+		}
+		top := stack[len(stack)-1]
+		if latest == nil || top.seq > latest.seq {
+			latest = &top
+		}
+	}
+	oe.mu.Unlock()
+	if latest != nil {
+		fn(latest.span)
+	}
+}
@@ -0,0 +1,94 @@
+package islruntime_test
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	islruntime "github.com/shipgate/isl-runtime-go"
+)
+
+func newRecordedOTelEmitter(t *testing.T) (*islruntime.OTelEmitter, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	tracer := tp.Tracer("islruntime-test")
+	return islruntime.NewOTelEmitter(tracer, "Orders", "PlaceOrder"), recorder
+}
+
+// TestOTelEmitterEmitCallContextNesting checks that EmitCallContext links a
+// nested call's span to its caller's span via context, so ISL verification
+// spans for a behavior that calls another instrumented function show up as
+// a single causal tree rather than disconnected traces.
+func TestOTelEmitterEmitCallContextNesting(t *testing.T) {
+	oe, recorder := newRecordedOTelEmitter(t)
+
+	outerCtx := oe.EmitCallContext(context.Background(), "Outer", nil)
+	innerCtx := oe.EmitCallContext(outerCtx, "Inner", nil)
+	oe.EmitReturn("Inner", nil, 1)
+	oe.EmitReturn("Outer", nil, 2)
+	_ = innerCtx
+
+	ended := recorder.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("len(ended spans) = %d, want 2", len(ended))
+	}
+
+	var outer, inner sdktrace.ReadOnlySpan
+	for _, s := range ended {
+		switch s.Name() {
+		case "Outer":
+			outer = s
+		case "Inner":
+			inner = s
+		}
+	}
+	if outer == nil || inner == nil {
+		t.Fatal("expected spans named Outer and Inner")
+	}
+	if inner.Parent().SpanID() != outer.SpanContext().SpanID() {
+		t.Errorf("Inner span's parent SpanID = %v, want Outer span's SpanID %v", inner.Parent().SpanID(), outer.SpanContext().SpanID())
+	}
+}
+
+// TestOTelEmitterConcurrentCallsToSameFunction checks that two in-flight
+// calls to the same functionName each get their own span rather than the
+// second EmitCall clobbering the first's entry in the active-span map, which
+// used to happen when active spans were keyed by a single map[string]
+// entry instead of a per-functionName stack.
+func TestOTelEmitterConcurrentCallsToSameFunction(t *testing.T) {
+	oe, recorder := newRecordedOTelEmitter(t)
+
+	oe.EmitCall("Recurse", map[string]interface{}{"depth": 1})
+	oe.EmitCall("Recurse", map[string]interface{}{"depth": 2})
+	oe.EmitReturn("Recurse", "inner-result", 1)
+	oe.EmitReturn("Recurse", "outer-result", 2)
+
+	ended := recorder.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("len(ended spans) = %d, want 2 (each EmitCall should get its own span)", len(ended))
+	}
+	if ended[0].SpanContext().SpanID() == ended[1].SpanContext().SpanID() {
+		t.Error("both calls to \"Recurse\" ended the same span")
+	}
+}
+
+func TestOTelEmitterEmitErrorSetsStatus(t *testing.T) {
+	oe, recorder := newRecordedOTelEmitter(t)
+
+	oe.EmitCall("Validate", nil)
+	code := "BAD_INPUT"
+	oe.EmitError("validation failed", &code, nil)
+	oe.EmitReturn("Validate", nil, 1)
+
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("len(ended spans) = %d, want 1", len(ended))
+	}
+	if ended[0].Status().Code.String() != "Error" {
+		t.Errorf("span status = %v, want Error", ended[0].Status().Code)
+	}
+}
@@ -0,0 +1,52 @@
+package parser
+
+// Position identifies a location in an ISL source file.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Domain is the root node of a parsed ISL file.
+type Domain struct {
+	Name             string
+	Behaviors        []*Behavior
+	GlobalInvariants []*Invariant
+	Pos              Position
+}
+
+// Behavior is a `behavior Name { ... }` block.
+type Behavior struct {
+	Name           string
+	Preconditions  []*Precondition
+	Postconditions []*Postcondition
+	Invariants     []*Invariant
+	Pos            Position
+}
+
+// Precondition is a `precondition <expr>;` statement inside a behavior.
+type Precondition struct {
+	Expr *Expression
+	Pos  Position
+}
+
+// Postcondition is a `postcondition <expr>;` statement inside a behavior.
+type Postcondition struct {
+	Expr *Expression
+	Pos  Position
+}
+
+// Invariant is an `invariant <expr>;` statement, either inside a behavior
+// or at domain scope (a global invariant).
+type Invariant struct {
+	Expr *Expression
+	Pos  Position
+}
+
+// Expression holds the raw source text of a constraint expression (e.g.
+// `input.email.length > 0` or `result.id != null`), exactly as written,
+// including any internal newlines. Evaluating it is the job of
+// islruntime.Evaluator, not this package.
+type Expression struct {
+	Text string
+	Pos  Position
+}
@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Lexer tokenizes ISL source: identifiers, string literals, numbers,
+// operators, braces/parens, semicolons, and `//` / `/* */` comments
+// (comments are skipped, never emitted as tokens).
+type Lexer struct {
+	src    []rune
+	pos    int
+	line   int
+	column int
+}
+
+// NewLexer creates a Lexer over src.
+func NewLexer(src string) *Lexer {
+	return &Lexer{src: []rune(src), pos: 0, line: 1, column: 1}
+}
+
+// Next returns the next token, skipping whitespace and comments.
+func (l *Lexer) Next() (Token, error) {
+	if err := l.skipWhitespaceAndComments(); err != nil {
+		return Token{}, err
+	}
+
+	start := Token{Offset: l.pos, Line: l.line, Column: l.column}
+
+	if l.pos >= len(l.src) {
+		start.Type = TokenEOF
+		return start, nil
+	}
+
+	ch := l.src[l.pos]
+
+	var tok Token
+	var err error
+	switch {
+	case ch == '{':
+		l.advance()
+		start.Type, start.Value = TokenLBrace, "{"
+		tok = start
+	case ch == '}':
+		l.advance()
+		start.Type, start.Value = TokenRBrace, "}"
+		tok = start
+	case ch == '(':
+		l.advance()
+		start.Type, start.Value = TokenLParen, "("
+		tok = start
+	case ch == ')':
+		l.advance()
+		start.Type, start.Value = TokenRParen, ")"
+		tok = start
+	case ch == ';':
+		l.advance()
+		start.Type, start.Value = TokenSemicolon, ";"
+		tok = start
+	case ch == '"' || ch == '\'':
+		tok, err = l.lexString(start, ch)
+	case unicode.IsDigit(ch):
+		tok, err = l.lexNumber(start)
+	case isIdentStart(ch):
+		tok, err = l.lexIdent(start)
+	case isOperatorRune(ch):
+		tok, err = l.lexOperator(start)
+	default:
+		return Token{}, &ParseError{Line: start.Line, Column: start.Column, Message: fmt.Sprintf("unexpected character %q", ch)}
+	}
+	if err != nil {
+		return Token{}, err
+	}
+	// Recorded here rather than in each sub-lexer so every return path gets
+	// it for free: the raw rune span a token consumed, which (unlike
+	// reconstructing from Value) stays correct for string literals, whose
+	// Value is unescaped and so shorter than the source text it came from.
+	tok.EndOffset = l.pos
+	return tok, nil
+}
+
+func (l *Lexer) skipWhitespaceAndComments() error {
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			l.advance()
+		case ch == '/' && l.peek(1) == '/':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance()
+			}
+		case ch == '/' && l.peek(1) == '*':
+			startLine, startColumn := l.line, l.column
+			l.advance()
+			l.advance()
+			for l.pos < len(l.src) && !(l.src[l.pos] == '*' && l.peek(1) == '/') {
+				l.advance()
+			}
+			if l.pos >= len(l.src) {
+				return &ParseError{Line: startLine, Column: startColumn, Message: "unterminated comment"}
+			}
+			l.advance()
+			l.advance()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *Lexer) lexString(start Token, quote rune) (Token, error) {
+	l.advance() // opening quote
+	var sb strings.Builder
+	for {
+		if l.pos >= len(l.src) {
+			return Token{}, &ParseError{Line: start.Line, Column: start.Column, Message: "unterminated string literal"}
+		}
+		ch := l.src[l.pos]
+		if ch == quote {
+			l.advance()
+			break
+		}
+		if ch == '\\' && l.peek(1) != 0 {
+			l.advance()
+			sb.WriteRune(l.src[l.pos])
+			l.advance()
+			continue
+		}
+		sb.WriteRune(ch)
+		l.advance()
+	}
+	start.Type = TokenString
+	start.Value = sb.String()
+	return start, nil
+}
+
+func (l *Lexer) lexNumber(start Token) (Token, error) {
+	from := l.pos
+	for l.pos < len(l.src) && (unicode.IsDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+		l.advance()
+	}
+	start.Type = TokenNumber
+	start.Value = string(l.src[from:l.pos])
+	return start, nil
+}
+
+func (l *Lexer) lexIdent(start Token) (Token, error) {
+	from := l.pos
+	for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+		l.advance()
+	}
+	start.Type = TokenIdent
+	start.Value = string(l.src[from:l.pos])
+	return start, nil
+}
+
+// multiCharOperators are checked longest-first so `==` isn't lexed as two
+// `=` operators, etc.
+var multiCharOperators = []string{"==", "!=", ">=", "<=", "&&", "||"}
+
+func (l *Lexer) lexOperator(start Token) (Token, error) {
+	for _, op := range multiCharOperators {
+		if l.hasPrefix(op) {
+			for range op {
+				l.advance()
+			}
+			start.Type, start.Value = TokenOperator, op
+			return start, nil
+		}
+	}
+	ch := l.src[l.pos]
+	l.advance()
+	start.Type, start.Value = TokenOperator, string(ch)
+	return start, nil
+}
+
+func (l *Lexer) hasPrefix(s string) bool {
+	runes := []rune(s)
+	if l.pos+len(runes) > len(l.src) {
+		return false
+	}
+	for i, r := range runes {
+		if l.src[l.pos+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Lexer) peek(offset int) rune {
+	if l.pos+offset >= len(l.src) {
+		return 0
+	}
+	return l.src[l.pos+offset]
+}
+
+func (l *Lexer) advance() {
+	if l.src[l.pos] == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	l.pos++
+}
+
+func isIdentStart(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+func isIdentPart(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_' || ch == '.'
+}
+
+func isOperatorRune(ch rune) bool {
+	return strings.ContainsRune("=!<>&|+-*/,!", ch)
+}
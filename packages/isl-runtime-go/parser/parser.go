@@ -0,0 +1,251 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError reports a syntax error at a specific line/column in an ISL
+// source file.
+type ParseError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// Parser is a recursive-descent parser over the token stream produced by
+// Lexer. Grammar (informally):
+//
+//	file       := { domainDecl | behaviorDecl | invariantDecl }
+//	domainDecl := "domain" IDENT [ ";" ]
+//	behaviorDecl := "behavior" IDENT "{" { preDecl | postDecl | invariantDecl } "}"
+//	preDecl    := "precondition" expr ";"
+//	postDecl   := "postcondition" expr ";"
+//	invariantDecl := "invariant" expr ";"
+//	expr       := any tokens up to the matching top-level ";"
+//
+// expr is captured as raw source text (not a sub-AST): nested parens are
+// tracked only so a ";" inside, say, a function-call-style expression
+// doesn't end the statement early; string literals are already single
+// tokens, so keywords or ";" inside a string literal never confuse it.
+type Parser struct {
+	src    string
+	lexer  *Lexer
+	tok    Token
+	peeked *Token
+}
+
+// NewParser creates a Parser over src.
+func NewParser(src string) (*Parser, error) {
+	p := &Parser{src: src, lexer: NewLexer(src)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Parse parses the whole file into a Domain.
+func (p *Parser) Parse() (*Domain, error) {
+	domain := &Domain{Name: "Unknown"}
+
+	for p.tok.Type != TokenEOF {
+		switch {
+		case p.tok.Type == TokenIdent && p.tok.Value == "domain":
+			if err := p.parseDomainDecl(domain); err != nil {
+				return nil, err
+			}
+		case p.tok.Type == TokenIdent && p.tok.Value == "behavior":
+			behavior, err := p.parseBehaviorDecl()
+			if err != nil {
+				return nil, err
+			}
+			domain.Behaviors = append(domain.Behaviors, behavior)
+		case p.tok.Type == TokenIdent && p.tok.Value == "invariant":
+			inv, err := p.parseInvariantDecl()
+			if err != nil {
+				return nil, err
+			}
+			domain.GlobalInvariants = append(domain.GlobalInvariants, inv)
+		default:
+			return nil, p.errorf("expected 'domain', 'behavior', or 'invariant', got %s", p.tok)
+		}
+	}
+
+	return domain, nil
+}
+
+func (p *Parser) parseDomainDecl(domain *Domain) error {
+	domain.Pos = Position{Line: p.tok.Line, Column: p.tok.Column}
+	if err := p.advance(); err != nil { // consume "domain"
+		return err
+	}
+	name, err := p.expect(TokenIdent)
+	if err != nil {
+		return err
+	}
+	domain.Name = name.Value
+	return p.consumeOptional(TokenSemicolon)
+}
+
+func (p *Parser) parseBehaviorDecl() (*Behavior, error) {
+	pos := Position{Line: p.tok.Line, Column: p.tok.Column}
+	if err := p.advance(); err != nil { // consume "behavior"
+		return nil, err
+	}
+	name, err := p.expect(TokenIdent)
+	if err != nil {
+		return nil, err
+	}
+	behavior := &Behavior{Name: name.Value, Pos: pos}
+
+	if _, err := p.expect(TokenLBrace); err != nil {
+		return nil, err
+	}
+
+	for p.tok.Type != TokenRBrace {
+		if p.tok.Type == TokenEOF {
+			return nil, p.errorf("unterminated behavior %q: expected '}'", behavior.Name)
+		}
+		if p.tok.Type != TokenIdent {
+			return nil, p.errorf("expected precondition/postcondition/invariant, got %s", p.tok)
+		}
+		switch p.tok.Value {
+		case "precondition":
+			pre, err := p.parsePrecondition()
+			if err != nil {
+				return nil, err
+			}
+			behavior.Preconditions = append(behavior.Preconditions, pre)
+		case "postcondition":
+			post, err := p.parsePostcondition()
+			if err != nil {
+				return nil, err
+			}
+			behavior.Postconditions = append(behavior.Postconditions, post)
+		case "invariant":
+			inv, err := p.parseInvariantDecl()
+			if err != nil {
+				return nil, err
+			}
+			behavior.Invariants = append(behavior.Invariants, inv)
+		default:
+			return nil, p.errorf("unexpected keyword %q inside behavior %q", p.tok.Value, behavior.Name)
+		}
+	}
+	if _, err := p.expect(TokenRBrace); err != nil {
+		return nil, err
+	}
+	return behavior, nil
+}
+
+func (p *Parser) parsePrecondition() (*Precondition, error) {
+	pos := Position{Line: p.tok.Line, Column: p.tok.Column}
+	if err := p.advance(); err != nil { // consume "precondition"
+		return nil, err
+	}
+	expr, err := p.parseExpressionStatement()
+	if err != nil {
+		return nil, err
+	}
+	return &Precondition{Expr: expr, Pos: pos}, nil
+}
+
+func (p *Parser) parsePostcondition() (*Postcondition, error) {
+	pos := Position{Line: p.tok.Line, Column: p.tok.Column}
+	if err := p.advance(); err != nil { // consume "postcondition"
+		return nil, err
+	}
+	expr, err := p.parseExpressionStatement()
+	if err != nil {
+		return nil, err
+	}
+	return &Postcondition{Expr: expr, Pos: pos}, nil
+}
+
+func (p *Parser) parseInvariantDecl() (*Invariant, error) {
+	pos := Position{Line: p.tok.Line, Column: p.tok.Column}
+	if err := p.advance(); err != nil { // consume "invariant"
+		return nil, err
+	}
+	expr, err := p.parseExpressionStatement()
+	if err != nil {
+		return nil, err
+	}
+	return &Invariant{Expr: expr, Pos: pos}, nil
+}
+
+// parseExpressionStatement captures the raw source text from the current
+// token up to (but not including) the terminating top-level ";", so
+// expressions can span multiple lines, contain nested parens, and contain
+// string literals with keywords or ";" inside them without confusing the
+// statement boundary.
+func (p *Parser) parseExpressionStatement() (*Expression, error) {
+	if p.tok.Type == TokenSemicolon || p.tok.Type == TokenRBrace || p.tok.Type == TokenEOF {
+		return nil, p.errorf("expected an expression, got %s", p.tok)
+	}
+	pos := Position{Line: p.tok.Line, Column: p.tok.Column}
+	start := p.tok.Offset
+
+	depth := 0
+	end := start
+	for {
+		if p.tok.Type == TokenEOF {
+			return nil, p.errorf("unterminated expression starting at %d:%d", pos.Line, pos.Column)
+		}
+		if depth == 0 && p.tok.Type == TokenSemicolon {
+			break
+		}
+		if p.tok.Type == TokenLParen {
+			depth++
+		}
+		if p.tok.Type == TokenRParen {
+			depth--
+		}
+		end = p.tok.EndOffset
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.advance(); err != nil { // consume ";"
+		return nil, err
+	}
+
+	runes := []rune(p.src)
+	if end > len(runes) {
+		end = len(runes)
+	}
+	text := strings.TrimSpace(string(runes[start:end]))
+	return &Expression{Text: text, Pos: pos}, nil
+}
+
+func (p *Parser) expect(t TokenType) (Token, error) {
+	if p.tok.Type != t {
+		return Token{}, p.errorf("expected %s, got %s", t, p.tok)
+	}
+	tok := p.tok
+	return tok, p.advance()
+}
+
+func (p *Parser) consumeOptional(t TokenType) error {
+	if p.tok.Type == t {
+		return p.advance()
+	}
+	return nil
+}
+
+func (p *Parser) advance() error {
+	tok, err := p.lexer.Next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *Parser) errorf(format string, args ...interface{}) error {
+	return &ParseError{Line: p.tok.Line, Column: p.tok.Column, Message: fmt.Sprintf(format, args...)}
+}
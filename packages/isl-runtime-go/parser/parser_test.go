@@ -0,0 +1,88 @@
+package parser_test
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shipgate/isl-runtime-go/parser"
+)
+
+var update = flag.Bool("update", false, "write golden files instead of comparing against them")
+
+// TestParserGolden parses every testdata/*.isl fixture and compares the
+// resulting AST (as indented JSON) against a checked-in testdata/*.golden.json
+// file. Run with -update to regenerate the golden files after an intentional
+// grammar or AST change.
+func TestParserGolden(t *testing.T) {
+	matches, err := filepath.Glob("testdata/*.isl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) == 0 {
+		t.Fatal("no .isl fixtures found in testdata")
+	}
+
+	for _, srcPath := range matches {
+		srcPath := srcPath
+		name := strings.TrimSuffix(filepath.Base(srcPath), ".isl")
+		t.Run(name, func(t *testing.T) {
+			src, err := os.ReadFile(srcPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := parser.NewParser(string(src))
+			if err != nil {
+				t.Fatalf("NewParser: %v", err)
+			}
+			domain, err := p.Parse()
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			got, err := json.MarshalIndent(domain, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			goldenPath := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("reading golden file (run with -update to create it): %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("golden mismatch for %s\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+			}
+		})
+	}
+}
+
+// TestUnterminatedBlockComment ensures an unclosed /* comment surfaces as a
+// ParseError with a line/column, rather than panicking.
+func TestUnterminatedBlockComment(t *testing.T) {
+	p, err := parser.NewParser("domain D;\nbehavior B {\nprecondition true; /* oops\n")
+	if err != nil {
+		t.Fatalf("NewParser: %v", err)
+	}
+	_, err = p.Parse()
+	if err == nil {
+		t.Fatal("expected an error for an unterminated block comment, got nil")
+	}
+	var parseErr *parser.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a *parser.ParseError, got %T: %v", err, err)
+	}
+}
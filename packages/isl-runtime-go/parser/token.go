@@ -0,0 +1,69 @@
+// Package parser implements a lexer and recursive-descent parser for ISL
+// (Intent Specification Language) spec files, producing a typed AST that
+// islruntime.ConstraintLoader lowers into DomainConstraints.
+package parser
+
+import "fmt"
+
+// TokenType identifies the lexical class of a Token.
+type TokenType int
+
+const (
+	TokenEOF TokenType = iota
+	TokenIdent
+	TokenString
+	TokenNumber
+	TokenLBrace
+	TokenRBrace
+	TokenLParen
+	TokenRParen
+	TokenSemicolon
+	TokenOperator
+)
+
+func (t TokenType) String() string {
+	switch t {
+	case TokenEOF:
+		return "EOF"
+	case TokenIdent:
+		return "identifier"
+	case TokenString:
+		return "string"
+	case TokenNumber:
+		return "number"
+	case TokenLBrace:
+		return "'{'"
+	case TokenRBrace:
+		return "'}'"
+	case TokenLParen:
+		return "'('"
+	case TokenRParen:
+		return "')'"
+	case TokenSemicolon:
+		return "';'"
+	case TokenOperator:
+		return "operator"
+	default:
+		return "unknown"
+	}
+}
+
+// Token is a single lexical token, tagged with its rune offset range in the
+// source (Offset is inclusive of the token's first rune, EndOffset
+// exclusive of its last) so the parser can slice out raw expression text
+// without reconstructing the span from Value — which, for a TokenString,
+// is the *unescaped* contents and so is shorter than the raw source text
+// whenever the literal contains a backslash escape. Line/Column are for
+// error reporting.
+type Token struct {
+	Type      TokenType
+	Value     string
+	Offset    int
+	EndOffset int
+	Line      int
+	Column    int
+}
+
+func (t Token) String() string {
+	return fmt.Sprintf("%s(%q) at %d:%d", t.Type, t.Value, t.Line, t.Column)
+}
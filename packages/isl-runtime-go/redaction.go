@@ -0,0 +1,412 @@
+package islruntime
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redactor scrubs sensitive values out of trace data before it reaches a
+// sink (TraceEmitter's JSON file, OTelEmitter's spans, or a streaming
+// writer). RuleBasedRedactor is the default implementation; swap in a
+// different one (e.g. a no-op for local debugging) via WithRedactor.
+type Redactor interface {
+	// RedactMap returns a copy of value with forbidden keys dropped and
+	// every remaining value passed through RedactValue (or Custom, if it
+	// claims the key).
+	RedactMap(value map[string]interface{}) map[string]interface{}
+	// RedactValue scrubs a single value, such as a function result or an
+	// old/new state value, that isn't associated with a key.
+	RedactValue(value interface{}) interface{}
+	// RedactString scrubs a single optional string, such as an error
+	// stack trace.
+	RedactString(value *string) *string
+}
+
+// ValueRule is a regexp-driven redaction rule: any match of Pattern within
+// a string value is replaced with Replacement (which may reference capture
+// groups as $1, $2, ... per regexp.ReplaceAllString).
+type ValueRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// RedactionPolicy configures a RuleBasedRedactor. The zero value adds no
+// rules on top of RuleBasedRedactor's always-on detectors (email, IPv4/IPv6,
+// Luhn-validated card numbers, and the email/ip/phone key formatters); use
+// DefaultRedactionPolicy for the rules TraceEmitter applied before this type
+// existed, plus the new built-in detectors.
+type RedactionPolicy struct {
+	// ForbiddenKeys lists key substrings (case-insensitive) whose values
+	// are dropped entirely rather than redacted in place, e.g. "password".
+	ForbiddenKeys []string
+	// KeyPatterns is the regexp equivalent of ForbiddenKeys, for keys that
+	// don't share a fixed substring (e.g. "user_ssn", "ssn_encrypted").
+	KeyPatterns []*regexp.Regexp
+	// ValuePatterns are applied, in order, to every string value that
+	// ForbiddenKeys/KeyPatterns/Custom didn't already claim. String values
+	// under an email/ip/phone-named key get that key's formatter instead and
+	// are not passed through ValuePatterns.
+	ValuePatterns []ValueRule
+	// Custom is consulted for every key/value pair before the built-in
+	// pipeline runs. Returning matched=true claims the pair and redacted
+	// is stored as-is (ForbiddenKeys/KeyPatterns/ValuePatterns are
+	// skipped for it); returning matched=false falls through to them.
+	Custom func(key string, val interface{}) (redacted interface{}, matched bool)
+}
+
+// DefaultRedactionPolicy is the policy RuleBasedRedactor uses when none is
+// given: the forbidden-key list TraceEmitter always applied, plus JWTs, AWS
+// access keys and bearer tokens as built-in value patterns. Email addresses,
+// credit card numbers and IPv4/IPv6 addresses are always detected regardless
+// of policy; see RuleBasedRedactor.RedactValue.
+func DefaultRedactionPolicy() RedactionPolicy {
+	return RedactionPolicy{
+		ForbiddenKeys: []string{
+			"password", "password_hash", "secret", "api_key", "apikey",
+			"access_token", "accesstoken", "refresh_token", "refreshtoken",
+			"private_key", "privatekey", "credit_card", "creditcard",
+			"ssn", "social_security",
+		},
+		ValuePatterns: []ValueRule{
+			jwtValueRule,
+			awsAccessKeyValueRule,
+			bearerTokenValueRule,
+		},
+	}
+}
+
+var (
+	jwtValueRule = ValueRule{
+		Pattern:     regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+		Replacement: "***REDACTED-JWT***",
+	}
+	awsAccessKeyValueRule = ValueRule{
+		Pattern:     regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
+		Replacement: "***REDACTED-AWS-KEY***",
+	}
+	bearerTokenValueRule = ValueRule{
+		Pattern:     regexp.MustCompile(`(?i)\bBearer\s+[A-Za-z0-9\-._~+/]+=*`),
+		Replacement: "Bearer ***REDACTED***",
+	}
+	ipv4Regexp       = regexp.MustCompile(`^\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}$`)
+	ipv6Regexp       = regexp.MustCompile(`^[0-9a-fA-F:]*:[0-9a-fA-F:]*$`)
+	creditCardRegexp = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+
+	// Unanchored counterparts of the above, used by redactString to find
+	// and mask an email/IP embedded in a larger string (e.g. a log line)
+	// rather than only matching when the whole value is nothing but an
+	// address.
+	emailSubstringRegexp = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	ipv4SubstringRegexp  = regexp.MustCompile(`\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}`)
+	ipv6SubstringRegexp  = regexp.MustCompile(`(?:[0-9a-fA-F]{1,4}:){2,7}[0-9a-fA-F]{1,4}`)
+	hexLetterGroupRegexp = regexp.MustCompile(`[a-fA-F]`)
+)
+
+// RuleBasedRedactor is the default Redactor: a fixed set of always-on
+// detectors (email, IPv4/IPv6, Luhn-validated credit card numbers) plus
+// whatever ForbiddenKeys/KeyPatterns/ValuePatterns/Custom a RedactionPolicy
+// adds on top.
+type RuleBasedRedactor struct {
+	policy RedactionPolicy
+}
+
+var _ Redactor = (*RuleBasedRedactor)(nil)
+
+// NewRuleBasedRedactor creates a redactor configured by policy.
+func NewRuleBasedRedactor(policy RedactionPolicy) *RuleBasedRedactor {
+	return &RuleBasedRedactor{policy: policy}
+}
+
+// RedactMap implements Redactor.
+func (r *RuleBasedRedactor) RedactMap(value map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(value))
+	for k, v := range value {
+		if r.policy.Custom != nil {
+			if customVal, matched := r.policy.Custom(k, v); matched {
+				redacted[k] = customVal
+				continue
+			}
+		}
+		lowerKey := strings.ToLower(k)
+		if r.keyForbidden(lowerKey) {
+			continue
+		}
+		if str, ok := v.(string); ok && formatterForKey(lowerKey) != nil {
+			redacted[k] = formatterForKey(lowerKey)(str)
+			continue
+		}
+		redacted[k] = r.RedactValue(v)
+	}
+	return redacted
+}
+
+func (r *RuleBasedRedactor) keyForbidden(lowerKey string) bool {
+	for _, f := range r.policy.ForbiddenKeys {
+		if strings.Contains(lowerKey, strings.ToLower(f)) {
+			return true
+		}
+	}
+	for _, p := range r.policy.KeyPatterns {
+		if p.MatchString(lowerKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatterForKey returns the key-name-driven formatter (email/ip/phone)
+// TraceEmitter used before RedactionPolicy existed, so e.g. a field named
+// "home_ip" still gets the structured "a.b.xxx.xxx" treatment instead of
+// falling through to the generic value scan.
+func formatterForKey(lowerKey string) func(string) string {
+	switch {
+	case strings.Contains(lowerKey, "email"):
+		return redactEmail
+	case strings.Contains(lowerKey, "ip") || lowerKey == "ip_address":
+		return redactIPKeyed
+	case strings.Contains(lowerKey, "phone"):
+		return redactPhone
+	default:
+		return nil
+	}
+}
+
+// redactIPKeyed redacts a value behind an "ip"-named key, picking the IPv4
+// or IPv6 formatter based on the value itself rather than assuming IPv4 the
+// way the original key-name-driven formatter did.
+func redactIPKeyed(ip string) string {
+	if ipv4Regexp.MatchString(ip) {
+		return redactIPString(ip)
+	}
+	if looksLikeIPv6(ip) {
+		return redactIPv6(ip)
+	}
+	return redactIPString(ip)
+}
+
+// RedactValue implements Redactor.
+func (r *RuleBasedRedactor) RedactValue(value interface{}) interface{} {
+	str, ok := value.(string)
+	if !ok {
+		return value
+	}
+	return r.redactString(str)
+}
+
+// RedactString implements Redactor.
+func (r *RuleBasedRedactor) RedactString(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	redacted := r.redactString(*value)
+	return &redacted
+}
+
+// redactString runs every detector as a pass over str, in order, so a
+// match for one (e.g. an email address) doesn't short-circuit the rest
+// (e.g. a JWT later in the same string) the way an early return would.
+func (r *RuleBasedRedactor) redactString(str string) string {
+	str = emailSubstringRegexp.ReplaceAllStringFunc(str, redactEmail)
+	str = ipv4SubstringRegexp.ReplaceAllStringFunc(str, redactIPString)
+	str = ipv6SubstringRegexp.ReplaceAllStringFunc(str, func(match string) string {
+		if !hasIPv6Shape(match) {
+			return match
+		}
+		return redactIPv6(match)
+	})
+	str = creditCardRegexp.ReplaceAllStringFunc(str, func(match string) string {
+		digits := stripNonDigits(match)
+		if len(digits) < 13 || len(digits) > 19 || !isLuhnValid(digits) {
+			return match
+		}
+		return maskCardDigits(digits)
+	})
+	for _, rule := range r.policy.ValuePatterns {
+		str = rule.Pattern.ReplaceAllString(str, rule.Replacement)
+	}
+	return str
+}
+
+func redactEmail(email string) string {
+	parts := strings.Split(email, "@")
+	if len(parts) != 2 {
+		return "***@***"
+	}
+	local := parts[0]
+	domain := parts[1]
+	redactedLocal := "*"
+	if len(local) > 1 {
+		redactedLocal = string(local[0]) + strings.Repeat("*", min(len(local)-1, 3))
+	}
+	return fmt.Sprintf("%s@%s", redactedLocal, domain)
+}
+
+func redactIPString(ip string) string {
+	parts := strings.Split(ip, ".")
+	if len(parts) == 4 {
+		return fmt.Sprintf("%s.%s.xxx.xxx", parts[0], parts[1])
+	}
+	return "xxx.xxx.xxx.xxx"
+}
+
+// looksLikeIPv6 is deliberately loose (anything colon-separated hex) since
+// the runtime only needs to catch values worth masking, not validate that
+// they're well-formed addresses.
+func looksLikeIPv6(str string) bool {
+	return strings.Count(str, ":") >= 2 && ipv6Regexp.MatchString(str)
+}
+
+// hasIPv6Shape gates an ipv6SubstringRegexp match the way isLuhnValid gates
+// a credit-card match: the regexp alone can't tell a real address
+// ("2001:db8::1", "fe80::1") from plain decimal digits that merely contain
+// colons (a timestamp like "14:30:00", a version string like "1:2:3" —
+// decimal digits are a subset of hex digits). A "::" run is unambiguous
+// IPv6 zero-compression notation; short of that, requiring at least two
+// actual hex letters (a-f) somewhere in the match is enough to rule out
+// timestamps/versions, which are all decimal, while still catching
+// addresses, which are overwhelmingly likely to carry more than one.
+func hasIPv6Shape(match string) bool {
+	if strings.Contains(match, "::") {
+		return true
+	}
+	return len(hexLetterGroupRegexp.FindAllString(match, -1)) >= 2
+}
+
+func redactIPv6(ip string) string {
+	groups := strings.Split(ip, ":")
+	if len(groups) == 0 || groups[0] == "" {
+		return "xxxx:xxxx:xxxx:xxxx"
+	}
+	return fmt.Sprintf("%s:xxxx:xxxx:xxxx", groups[0])
+}
+
+func redactPhone(phone string) string {
+	if len(phone) > 4 {
+		return strings.Repeat("*", len(phone)-4) + phone[len(phone)-4:]
+	}
+	return "****"
+}
+
+func stripNonDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isLuhnValid reports whether digits passes the Luhn checksum, so the
+// credit-card detector doesn't flag order IDs or other numeric strings
+// that merely happen to be card-number length.
+func isLuhnValid(digits string) bool {
+	sum := 0
+	alternate := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if alternate {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		alternate = !alternate
+	}
+	return sum%10 == 0
+}
+
+func maskCardDigits(digits string) string {
+	if len(digits) <= 4 {
+		return strings.Repeat("*", len(digits))
+	}
+	return strings.Repeat("*", len(digits)-4) + digits[len(digits)-4:]
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// defaultRedactor is shared by the emitters (OTelEmitter, StreamingEmitter,
+// RotatingFileEmitter) that don't yet have a way to accept a custom
+// Redactor. TraceEmitter uses it unless NewTraceEmitter is given
+// WithRedactor.
+var defaultRedactor Redactor = NewRuleBasedRedactor(DefaultRedactionPolicy())
+
+func redactPII(value map[string]interface{}) map[string]interface{} {
+	return defaultRedactor.RedactMap(value)
+}
+
+func redactValue(value interface{}) interface{} {
+	return defaultRedactor.RedactValue(value)
+}
+
+func redactPIIValue(value *string) *string {
+	return defaultRedactor.RedactString(value)
+}
+
+// redactionPolicyFile is the on-disk shape of a RedactionPolicy, so ops
+// teams can tune redaction via YAML/JSON config instead of recompiling.
+// Custom is inherently code, not data, so it is never loaded from file;
+// callers that need it must set policy.Custom after loading.
+type redactionPolicyFile struct {
+	ForbiddenKeys []string `yaml:"forbidden_keys" json:"forbidden_keys"`
+	KeyPatterns   []string `yaml:"key_patterns" json:"key_patterns"`
+	ValuePatterns []struct {
+		Pattern     string `yaml:"pattern" json:"pattern"`
+		Replacement string `yaml:"replacement" json:"replacement"`
+	} `yaml:"value_patterns" json:"value_patterns"`
+}
+
+// LoadRedactionPolicyFromFile loads a RedactionPolicy from a YAML (.yaml,
+// .yml) or JSON (.json) file, layering ForbiddenKeys/KeyPatterns/
+// ValuePatterns on top of DefaultRedactionPolicy so ops teams only need to
+// specify what they're adding.
+func LoadRedactionPolicyFromFile(path string) (*RedactionPolicy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction policy file: %w", err)
+	}
+
+	var cfg redactionPolicyFile
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(content, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse redaction policy YAML: %w", err)
+		}
+	case ".json":
+		if err := json.Unmarshal(content, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse redaction policy JSON: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported redaction policy file extension %q", ext)
+	}
+
+	policy := DefaultRedactionPolicy()
+	policy.ForbiddenKeys = append(policy.ForbiddenKeys, cfg.ForbiddenKeys...)
+	for _, pattern := range cfg.KeyPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key pattern %q: %w", pattern, err)
+		}
+		policy.KeyPatterns = append(policy.KeyPatterns, re)
+	}
+	for _, vp := range cfg.ValuePatterns {
+		re, err := regexp.Compile(vp.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value pattern %q: %w", vp.Pattern, err)
+		}
+		policy.ValuePatterns = append(policy.ValuePatterns, ValueRule{Pattern: re, Replacement: vp.Replacement})
+	}
+	return &policy, nil
+}
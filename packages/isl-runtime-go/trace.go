@@ -2,11 +2,12 @@
 package islruntime
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"regexp"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,16 +26,18 @@ const (
 
 // TraceEvent represents a single trace event
 type TraceEvent struct {
-	ID          string                 `json:"id"`
-	Type        TraceEventType         `json:"type"`
-	Timestamp   int64                  `json:"timestamp"`
-	Data        map[string]interface{} `json:"data"`
-	Behavior    *string                `json:"behavior,omitempty"`
-	Input       map[string]interface{} `json:"input,omitempty"`
-	Output      interface{}            `json:"output,omitempty"`
-	Error       *ErrorInfo             `json:"error,omitempty"`
-	StateBefore *EntityStoreSnapshot   `json:"state_before,omitempty"`
-	StateAfter  *EntityStoreSnapshot   `json:"state_after,omitempty"`
+	ID           string                 `json:"id"`
+	Type         TraceEventType         `json:"type"`
+	Timestamp    int64                  `json:"timestamp"`
+	Data         map[string]interface{} `json:"data"`
+	Behavior     *string                `json:"behavior,omitempty"`
+	Input        map[string]interface{} `json:"input,omitempty"`
+	Output       interface{}            `json:"output,omitempty"`
+	Error        *ErrorInfo             `json:"error,omitempty"`
+	StateBefore  *EntityStoreSnapshot   `json:"state_before,omitempty"`
+	StateAfter   *EntityStoreSnapshot   `json:"state_after,omitempty"`
+	SpanID       string                 `json:"span_id,omitempty"`
+	ParentSpanID string                 `json:"parent_span_id,omitempty"`
 }
 
 // ErrorInfo represents error information
@@ -50,14 +53,14 @@ type EntityStoreSnapshot struct {
 
 // TraceMetadata contains metadata about a trace
 type TraceMetadata struct {
-	TestName      string  `json:"test_name"`
-	Scenario      string  `json:"scenario"`
+	TestName       string  `json:"test_name"`
+	Scenario       string  `json:"scenario"`
 	Implementation *string `json:"implementation,omitempty"`
-	Version       string  `json:"version"`
-	Environment   string  `json:"environment"`
-	Passed        bool    `json:"passed"`
-	FailureIndex  *int    `json:"failure_index,omitempty"`
-	Duration      int64   `json:"duration"`
+	Version        string  `json:"version"`
+	Environment    string  `json:"environment"`
+	Passed         bool    `json:"passed"`
+	FailureIndex   *int    `json:"failure_index,omitempty"`
+	Duration       int64   `json:"duration"`
 }
 
 // Trace represents a complete trace
@@ -69,43 +72,147 @@ type Trace struct {
 	EndTime      int64                  `json:"end_time"`
 	Events       []TraceEvent           `json:"events"`
 	InitialState map[string]interface{} `json:"initial_state"`
-	Snapshots    []interface{}           `json:"snapshots"`
-	Metadata     TraceMetadata           `json:"metadata"`
+	Snapshots    []interface{}          `json:"snapshots"`
+	Metadata     TraceMetadata          `json:"metadata"`
+}
+
+// Emitter is the surface every trace sink implements: TraceEmitter (JSON
+// files), OTelEmitter (OpenTelemetry spans), and any future destination.
+// Instrumentation code should be written against Emitter so it can be
+// pointed at a different sink without changes.
+type Emitter interface {
+	CaptureInitialState(state map[string]interface{})
+	EmitCall(functionName string, args map[string]interface{})
+	EmitReturn(functionName string, result interface{}, durationMs int64)
+	EmitStateChange(path []string, oldValue, newValue interface{}, source string)
+	EmitCheck(expression string, passed bool, category string, expected, actual interface{}, message *string)
+	EmitError(message string, code *string, stack *string)
 }
 
-// TraceEmitter emits trace events during runtime execution
+var _ Emitter = (*TraceEmitter)(nil)
+
+// TraceEmitter emits trace events during runtime execution.
+//
+// TraceEmitter is safe for concurrent use: EmitCall/EmitReturn/etc. may be
+// called from multiple goroutines (e.g. an HTTP handler serving parallel
+// requests, or a behavior that fans out internally) without losing events.
 type TraceEmitter struct {
+	mu           sync.Mutex
 	traceID      string
 	startTime    int64
 	events       []TraceEvent
 	initialState map[string]interface{}
 	domain       string
 	behavior     string
-	eventCounter int
+	eventCounter atomic.Int64
+	spanCounter  atomic.Int64
+	redactor     Redactor
+}
+
+// TraceEmitterOption configures optional TraceEmitter behavior.
+type TraceEmitterOption func(*TraceEmitter)
+
+// WithRedactor overrides the Redactor TraceEmitter uses to scrub PII,
+// replacing the default RuleBasedRedactor(DefaultRedactionPolicy()). Use
+// this to load a RedactionPolicy tuned for a specific domain (e.g. one
+// with KeyPatterns for national IDs) via LoadRedactionPolicyFromFile.
+func WithRedactor(redactor Redactor) TraceEmitterOption {
+	return func(te *TraceEmitter) {
+		te.redactor = redactor
+	}
 }
 
 // NewTraceEmitter creates a new trace emitter
-func NewTraceEmitter(domain, behavior string) *TraceEmitter {
-	return &TraceEmitter{
+func NewTraceEmitter(domain, behavior string, opts ...TraceEmitterOption) *TraceEmitter {
+	te := &TraceEmitter{
 		traceID:      fmt.Sprintf("trace_%d_%s", time.Now().UnixMilli(), uuid.New().String()),
 		startTime:    time.Now().UnixMilli(),
 		events:       make([]TraceEvent, 0),
 		initialState: make(map[string]interface{}),
 		domain:       domain,
 		behavior:     behavior,
-		eventCounter: 0,
+		redactor:     defaultRedactor,
 	}
+	for _, opt := range opts {
+		opt(te)
+	}
+	return te
 }
 
 // CaptureInitialState captures the initial state
 func (te *TraceEmitter) CaptureInitialState(state map[string]interface{}) {
-	te.initialState = te.redactPII(state)
+	redacted := te.redactPII(state)
+	te.mu.Lock()
+	te.initialState = redacted
+	te.mu.Unlock()
+}
+
+type emitterContextKey struct{}
+
+type spanContextKey struct{}
+
+// WithEmitter returns a copy of ctx carrying emitter so that nested calls
+// (including calls in child goroutines that inherit ctx) can pick up the
+// active emitter via FromContext instead of having it threaded through
+// every function signature.
+func WithEmitter(ctx context.Context, emitter *TraceEmitter) context.Context {
+	return context.WithValue(ctx, emitterContextKey{}, emitter)
+}
+
+// FromContext returns the TraceEmitter stored in ctx by WithEmitter, if any.
+func FromContext(ctx context.Context) (*TraceEmitter, bool) {
+	emitter, ok := ctx.Value(emitterContextKey{}).(*TraceEmitter)
+	return emitter, ok
+}
+
+// EmitSpan starts a span named name, recording it as a child of whatever
+// span is active on ctx (if any), and returns a context carrying the new
+// span plus a function that ends it. Typical use:
+//
+//	ctx, end := emitter.EmitSpan(ctx, "ValidateInput")
+//	defer end()
+func (te *TraceEmitter) EmitSpan(ctx context.Context, name string) (context.Context, func()) {
+	spanID := fmt.Sprintf("span_%d", te.spanCounter.Add(1))
+	parentSpanID, _ := ctx.Value(spanContextKey{}).(string)
+	start := time.Now()
+
+	te.appendEvent(TraceEvent{
+		ID:           te.generateEventID(),
+		Type:         TraceEventTypeCall,
+		Timestamp:    start.UnixMilli(),
+		Data:         map[string]interface{}{"kind": "span_start", "name": name},
+		Behavior:     &te.behavior,
+		SpanID:       spanID,
+		ParentSpanID: parentSpanID,
+	})
+
+	return context.WithValue(ctx, spanContextKey{}, spanID), func() {
+		te.appendEvent(TraceEvent{
+			ID:        te.generateEventID(),
+			Type:      TraceEventTypeReturn,
+			Timestamp: time.Now().UnixMilli(),
+			Data: map[string]interface{}{
+				"kind":        "span_end",
+				"name":        name,
+				"duration_ms": time.Since(start).Milliseconds(),
+			},
+			Behavior:     &te.behavior,
+			SpanID:       spanID,
+			ParentSpanID: parentSpanID,
+		})
+	}
+}
+
+func (te *TraceEmitter) appendEvent(event TraceEvent) {
+	te.mu.Lock()
+	te.events = append(te.events, event)
+	te.mu.Unlock()
 }
 
 // EmitCall emits a function call event
 func (te *TraceEmitter) EmitCall(functionName string, args map[string]interface{}) {
 	redactedArgs := te.redactPII(args)
-	te.events = append(te.events, TraceEvent{
+	te.appendEvent(TraceEvent{
 		ID:        te.generateEventID(),
 		Type:      TraceEventTypeCall,
 		Timestamp: time.Now().UnixMilli(),
@@ -122,7 +229,7 @@ func (te *TraceEmitter) EmitCall(functionName string, args map[string]interface{
 // EmitReturn emits a function return event
 func (te *TraceEmitter) EmitReturn(functionName string, result interface{}, durationMs int64) {
 	redactedResult := te.redactValue(result)
-	te.events = append(te.events, TraceEvent{
+	te.appendEvent(TraceEvent{
 		ID:        te.generateEventID(),
 		Type:      TraceEventTypeReturn,
 		Timestamp: time.Now().UnixMilli(),
@@ -139,16 +246,16 @@ func (te *TraceEmitter) EmitReturn(functionName string, result interface{}, dura
 
 // EmitStateChange emits a state change event
 func (te *TraceEmitter) EmitStateChange(path []string, oldValue, newValue interface{}, source string) {
-	te.events = append(te.events, TraceEvent{
+	te.appendEvent(TraceEvent{
 		ID:        te.generateEventID(),
 		Type:      TraceEventTypeStateChange,
 		Timestamp: time.Now().UnixMilli(),
 		Data: map[string]interface{}{
-			"kind":      "state_change",
-			"path":      path,
-			"oldValue":  te.redactValue(oldValue),
-			"newValue":  te.redactValue(newValue),
-			"source":    source,
+			"kind":     "state_change",
+			"path":     path,
+			"oldValue": te.redactValue(oldValue),
+			"newValue": te.redactValue(newValue),
+			"source":   source,
 		},
 		Behavior: &te.behavior,
 	})
@@ -158,10 +265,10 @@ func (te *TraceEmitter) EmitStateChange(path []string, oldValue, newValue interf
 func (te *TraceEmitter) EmitCheck(expression string, passed bool, category string, expected, actual interface{}, message *string) {
 	eventType := TraceEventTypeCheck
 	data := map[string]interface{}{
-		"kind":      "check",
+		"kind":       "check",
 		"expression": expression,
-		"passed":    passed,
-		"category":  category,
+		"passed":     passed,
+		"category":   category,
 	}
 	if expected != nil {
 		data["expected"] = te.redactValue(expected)
@@ -173,7 +280,7 @@ func (te *TraceEmitter) EmitCheck(expression string, passed bool, category strin
 		data["message"] = *message
 	}
 
-	te.events = append(te.events, TraceEvent{
+	te.appendEvent(TraceEvent{
 		ID:        te.generateEventID(),
 		Type:      eventType,
 		Timestamp: time.Now().UnixMilli(),
@@ -188,7 +295,7 @@ func (te *TraceEmitter) EmitError(message string, code *string, stack *string) {
 	if code != nil {
 		errorCode = *code
 	}
-	te.events = append(te.events, TraceEvent{
+	te.appendEvent(TraceEvent{
 		ID:        te.generateEventID(),
 		Type:      TraceEventTypeError,
 		Timestamp: time.Now().UnixMilli(),
@@ -206,19 +313,65 @@ func (te *TraceEmitter) EmitError(message string, code *string, stack *string) {
 	})
 }
 
+// CheckPreconditions evaluates every precondition of behavior against input
+// using an Evaluator, emitting a check event for each and stopping at the
+// first one that fails or errors. This replaces having callers hand-evaluate
+// each precondition in Go, which can drift from the ISL spec.
+func (te *TraceEmitter) CheckPreconditions(behavior BehaviorConstraint, input map[string]interface{}) error {
+	te.mu.Lock()
+	state := te.initialState
+	te.mu.Unlock()
+	scope := Scope{Input: input, State: state, OldState: state}
+	return te.runChecks(behavior.Preconditions, "precondition", scope)
+}
+
+// CheckPostconditions evaluates every postcondition of behavior against
+// input, result, and state (with old(...) resolving against the state
+// captured by CaptureInitialState), emitting a check event for each.
+func (te *TraceEmitter) CheckPostconditions(behavior BehaviorConstraint, input map[string]interface{}, result interface{}, state map[string]interface{}) error {
+	te.mu.Lock()
+	oldState := te.initialState
+	te.mu.Unlock()
+	scope := Scope{Input: input, Result: result, State: state, OldState: oldState}
+	return te.runChecks(behavior.Postconditions, "postcondition", scope)
+}
+
+func (te *TraceEmitter) runChecks(expressions []string, category string, scope Scope) error {
+	evaluator := NewEvaluator()
+	for _, expression := range expressions {
+		passed, actual, err := evaluator.Evaluate(expression, scope)
+		if err != nil {
+			errMsg := err.Error()
+			te.EmitCheck(expression, false, category, nil, nil, &errMsg)
+			return fmt.Errorf("failed to evaluate %s %q: %w", category, expression, err)
+		}
+		te.EmitCheck(expression, passed, category, nil, actual, nil)
+		if !passed {
+			return fmt.Errorf("%s failed: %s", category, expression)
+		}
+	}
+	return nil
+}
+
 // Finalize finalizes and returns the trace
 func (te *TraceEmitter) Finalize(passed bool) *Trace {
 	endTime := time.Now().UnixMilli()
 	duration := endTime - te.startTime
 
+	te.mu.Lock()
+	events := make([]TraceEvent, len(te.events))
+	copy(events, te.events)
+	initialState := te.initialState
+	te.mu.Unlock()
+
 	return &Trace{
 		ID:           te.traceID,
 		Name:         fmt.Sprintf("%s - %s", te.domain, te.behavior),
 		Domain:       te.domain,
 		StartTime:    te.startTime,
 		EndTime:      endTime,
-		Events:       te.events,
-		InitialState: te.initialState,
+		Events:       events,
+		InitialState: initialState,
 		Snapshots:    make([]interface{}, 0),
 		Metadata: TraceMetadata{
 			TestName:    fmt.Sprintf("%s::%s", te.domain, te.behavior),
@@ -242,122 +395,23 @@ func (te *TraceEmitter) SaveToFile(path string, passed bool) error {
 }
 
 func (te *TraceEmitter) generateEventID() string {
-	te.eventCounter++
-	return fmt.Sprintf("evt_%d_%d", te.eventCounter, time.Now().UnixMilli())
+	return fmt.Sprintf("evt_%d_%d", te.eventCounter.Add(1), time.Now().UnixMilli())
 }
 
+// redactPII/redactValue/redactPIIValue delegate to te.redactor, which is
+// DefaultRedactionPolicy's RuleBasedRedactor unless NewTraceEmitter was
+// given WithRedactor. Other sinks, such as OTelEmitter, use the
+// package-level redactPII/redactValue/redactPIIValue functions in
+// redaction.go, which apply the same default redactor.
+
 func (te *TraceEmitter) redactPII(value map[string]interface{}) map[string]interface{} {
-	redacted := make(map[string]interface{})
-	for k, v := range value {
-		lowerKey := strings.ToLower(k)
-		if te.isForbiddenKey(lowerKey) {
-			continue
-		}
-		if strings.Contains(lowerKey, "email") {
-			if str, ok := v.(string); ok {
-				redacted[k] = te.redactEmail(str)
-			} else {
-				redacted[k] = te.redactValue(v)
-			}
-		} else if strings.Contains(lowerKey, "ip") || lowerKey == "ip_address" {
-			if str, ok := v.(string); ok {
-				redacted[k] = te.redactIP(str)
-			} else {
-				redacted[k] = te.redactValue(v)
-			}
-		} else if strings.Contains(lowerKey, "phone") {
-			if str, ok := v.(string); ok {
-				redacted[k] = te.redactPhone(str)
-			} else {
-				redacted[k] = te.redactValue(v)
-			}
-		} else {
-			redacted[k] = te.redactValue(v)
-		}
-	}
-	return redacted
+	return te.redactor.RedactMap(value)
 }
 
 func (te *TraceEmitter) redactValue(value interface{}) interface{} {
-	if str, ok := value.(string); ok {
-		if strings.Contains(str, "@") && strings.Contains(str, ".") {
-			return te.redactEmail(str)
-		}
-		if te.isIPAddress(str) {
-			return te.redactIP(str)
-		}
-	}
-	return value
+	return te.redactor.RedactValue(value)
 }
 
 func (te *TraceEmitter) redactPIIValue(value *string) *string {
-	if value == nil {
-		return nil
-	}
-	str := *value
-	if strings.Contains(str, "@") && strings.Contains(str, ".") {
-		redacted := te.redactEmail(str)
-		return &redacted
-	}
-	if te.isIPAddress(str) {
-		redacted := te.redactIP(str)
-		return &redacted
-	}
-	return value
-}
-
-func (te *TraceEmitter) redactEmail(email string) string {
-	parts := strings.Split(email, "@")
-	if len(parts) != 2 {
-		return "***@***"
-	}
-	local := parts[0]
-	domain := parts[1]
-	redactedLocal := "*"
-	if len(local) > 1 {
-		redactedLocal = string(local[0]) + strings.Repeat("*", min(len(local)-1, 3))
-	}
-	return fmt.Sprintf("%s@%s", redactedLocal, domain)
-}
-
-func (te *TraceEmitter) redactIP(ip string) string {
-	parts := strings.Split(ip, ".")
-	if len(parts) == 4 {
-		return fmt.Sprintf("%s.%s.xxx.xxx", parts[0], parts[1])
-	}
-	return "xxx.xxx.xxx.xxx"
-}
-
-func (te *TraceEmitter) redactPhone(phone string) string {
-	if len(phone) > 4 {
-		return strings.Repeat("*", len(phone)-4) + phone[len(phone)-4:]
-	}
-	return "****"
-}
-
-func (te *TraceEmitter) isForbiddenKey(key string) bool {
-	forbidden := []string{
-		"password", "password_hash", "secret", "api_key", "apikey",
-		"access_token", "accesstoken", "refresh_token", "refreshtoken",
-		"private_key", "privatekey", "credit_card", "creditcard",
-		"ssn", "social_security",
-	}
-	for _, f := range forbidden {
-		if strings.Contains(key, f) {
-			return true
-		}
-	}
-	return false
-}
-
-func (te *TraceEmitter) isIPAddress(str string) bool {
-	ipRegex := regexp.MustCompile(`^\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}$`)
-	return ipRegex.MatchString(str)
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+	return te.redactor.RedactString(value)
 }
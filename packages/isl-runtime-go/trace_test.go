@@ -0,0 +1,93 @@
+package islruntime_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	islruntime "github.com/shipgate/isl-runtime-go"
+)
+
+func TestTraceEmitterFinalize(t *testing.T) {
+	te := islruntime.NewTraceEmitter("Orders", "PlaceOrder")
+	te.CaptureInitialState(map[string]interface{}{"stock": float64(10)})
+	te.EmitCall("PlaceOrder", map[string]interface{}{"sku": "abc"})
+	te.EmitReturn("PlaceOrder", map[string]interface{}{"id": "order-1"}, 12)
+
+	trace := te.Finalize(true)
+	if trace.Domain != "Orders" {
+		t.Errorf("Domain = %q, want Orders", trace.Domain)
+	}
+	if !trace.Metadata.Passed {
+		t.Error("Metadata.Passed = false, want true")
+	}
+	if len(trace.Events) != 2 {
+		t.Fatalf("len(Events) = %d, want 2", len(trace.Events))
+	}
+	if trace.InitialState["stock"] != float64(10) {
+		t.Errorf("InitialState[\"stock\"] = %v, want 10", trace.InitialState["stock"])
+	}
+}
+
+// TestTraceEmitterConcurrentEmit exercises EmitCall/EmitReturn/EmitStateChange
+// from many goroutines at once, the way parallel requests sharing one
+// TraceEmitter would, and checks that Finalize sees every event. Run with
+// -race to catch a regression of the data race where Finalize used to read
+// te.events/te.initialState without holding te.mu.
+func TestTraceEmitterConcurrentEmit(t *testing.T) {
+	te := islruntime.NewTraceEmitter("Orders", "PlaceOrder")
+	te.CaptureInitialState(map[string]interface{}{"stock": float64(10)})
+
+	const goroutines = 20
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(i int) {
+			defer wg.Done()
+			te.EmitCall("PlaceOrder", map[string]interface{}{"n": i})
+			te.EmitReturn("PlaceOrder", map[string]interface{}{"n": i}, int64(i))
+		}(i)
+	}
+	wg.Wait()
+
+	trace := te.Finalize(true)
+	if len(trace.Events) != goroutines*2 {
+		t.Errorf("len(Events) = %d, want %d", len(trace.Events), goroutines*2)
+	}
+}
+
+// TestEmitSpanParentChild checks that a nested EmitSpan call picks up the
+// enclosing span as its parent via context, and that ending the inner span
+// doesn't affect the outer one.
+func TestEmitSpanParentChild(t *testing.T) {
+	te := islruntime.NewTraceEmitter("Orders", "PlaceOrder")
+	ctx, endOuter := te.EmitSpan(context.Background(), "Outer")
+	_, endInner := te.EmitSpan(ctx, "Inner")
+	endInner()
+	endOuter()
+
+	trace := te.Finalize(true)
+	var innerStart, outerStart islruntime.TraceEvent
+	for _, ev := range trace.Events {
+		if ev.Data["name"] == "Inner" && ev.Data["kind"] == "span_start" {
+			innerStart = ev
+		}
+		if ev.Data["name"] == "Outer" && ev.Data["kind"] == "span_start" {
+			outerStart = ev
+		}
+	}
+	if innerStart.ParentSpanID != outerStart.SpanID {
+		t.Errorf("Inner span's ParentSpanID = %q, want Outer span's SpanID %q", innerStart.ParentSpanID, outerStart.SpanID)
+	}
+}
+
+func TestTraceEmitterRedactsPII(t *testing.T) {
+	te := islruntime.NewTraceEmitter("Orders", "PlaceOrder")
+	te.EmitCall("PlaceOrder", map[string]interface{}{"email": "user@example.com"})
+
+	trace := te.Finalize(true)
+	input := trace.Events[0].Input
+	if input["email"] == "user@example.com" {
+		t.Error("EmitCall did not redact the email in its Input")
+	}
+}